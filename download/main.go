@@ -3,15 +3,44 @@ package main
 import (
 	"bufio"
 	"crypto/tls"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
 	"regexp"
+	"sync"
+	"time"
 )
 
+var urlRE = regexp.MustCompile(`(DT[0-9])/(.+\.(jpg|gif)$)`)
+
+const (
+	defaultWorkers = 8
+	maxRetries     = 5
+	initialBackoff = 500 * time.Millisecond
+)
+
+// downloadResult is one URL's outcome, used both to drive the progress
+// bar and to build the end-of-run failure summary.
+type downloadResult struct {
+	URL string `json:"url"`
+	Err string `json:"error,omitempty"`
+}
+
+// retryableError marks a failure worth retrying with backoff (a 5xx
+// response or a network-level error), as opposed to a permanent one
+// like a 404.
+type retryableError struct{ err error }
+
+func (e retryableError) Error() string { return e.err.Error() }
+
 func main() {
+	workers := flag.Int("workers", defaultWorkers, "number of concurrent downloads")
+	insecure := flag.Bool("insecure-skip-verify", false, "skip TLS certificate verification (not recommended)")
+	flag.Parse()
 
 	f, err := os.Open("./urls.txt")
 	if err != nil {
@@ -22,43 +51,179 @@ func main() {
 	var urls []string
 	scanner := bufio.NewScanner(f)
 	for scanner.Scan() {
-		urls = append(urls, scanner.Text())
+		if line := scanner.Text(); line != "" {
+			urls = append(urls, line)
+		}
 	}
-
 	if err := scanner.Err(); err != nil {
 		log.Fatal(err)
 	}
 
-	re := regexp.MustCompile(`(DT[0-9])/(.+\.(jpg|gif)$)`)
+	matched := urls[:0]
+	for _, u := range urls {
+		if urlRE.MatchString(u) {
+			matched = append(matched, u)
+		}
+	}
 
-	config := &tls.Config{
-		InsecureSkipVerify: true,
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: *insecure},
+		},
 	}
-	tr := &http.Transport{TLSClientConfig: config}
-	client := &http.Client{Transport: tr}
 
-	for _, v := range urls {
-		matches := re.FindStringSubmatch(v)
-		if len(matches) > 2 {
-			res, err := client.Get(v)
-			if err != nil {
-				log.Fatal(err)
-			}
-			if res.StatusCode != http.StatusOK {
-				log.Fatal(res.StatusCode)
-			}
-			defer res.Body.Close()
+	results := runDownloads(client, matched, *workers)
 
-			f, err := os.Create(fmt.Sprintf("%s_%s", matches[1], matches[2]))
-			if err != nil {
-				log.Fatal(err)
-			}
-			defer f.Close()
+	var failures []downloadResult
+	total := len(matched)
+	done := 0
+	for res := range results {
+		done++
+		fmt.Fprintf(os.Stderr, "\rdownloaded %d/%d", done, total)
+		if res.Err != "" {
+			failures = append(failures, res)
+		}
+	}
+	fmt.Fprintln(os.Stderr)
 
-			_, err = io.Copy(f, res.Body)
-			if err != nil {
-				log.Fatal(err)
+	summary, err := json.MarshalIndent(struct {
+		Total    int               `json:"total"`
+		Failed   int               `json:"failed"`
+		Failures []downloadResult `json:"failures,omitempty"`
+	}{Total: total, Failed: len(failures), Failures: failures}, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(summary))
+}
+
+// runDownloads fans rawURLs out across workers goroutines and streams
+// each result back on the returned channel as soon as it completes, so a
+// caller can report live progress instead of waiting for the whole batch.
+// The channel is closed once every URL has been attempted.
+func runDownloads(client *http.Client, rawURLs []string, workers int) <-chan downloadResult {
+	jobs := make(chan string)
+	results := make(chan downloadResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for rawURL := range jobs {
+				results <- download(client, rawURL)
 			}
+		}()
+	}
+
+	go func() {
+		for _, rawURL := range rawURLs {
+			jobs <- rawURL
+		}
+		close(jobs)
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// download fetches rawURL with retries, skipping anything that doesn't
+// match urlRE (the original fetcher silently ignored those too).
+func download(client *http.Client, rawURL string) downloadResult {
+	matches := urlRE.FindStringSubmatch(rawURL)
+	if len(matches) < 3 {
+		return downloadResult{}
+	}
+
+	dest := fmt.Sprintf("%s_%s", matches[1], matches[2])
+	partial := dest + ".partial"
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(initialBackoff * time.Duration(uint(1)<<uint(attempt-1)))
+		}
+
+		err := downloadOnce(client, rawURL, dest, partial)
+		if err == nil {
+			return downloadResult{URL: rawURL}
+		}
+
+		lastErr = err
+		if _, retryable := err.(retryableError); !retryable {
+			break
+		}
+	}
+
+	return downloadResult{URL: rawURL, Err: lastErr.Error()}
+}
+
+// downloadOnce does one attempt at fetching rawURL into dest, resuming
+// from a previous .partial file when the server advertises range
+// support via a HEAD request.
+func downloadOnce(client *http.Client, rawURL, dest, partial string) error {
+	var offset int64
+	if fi, err := os.Stat(partial); err == nil {
+		offset = fi.Size()
+	}
+
+	if offset > 0 {
+		head, err := client.Head(rawURL)
+		if err != nil {
+			return retryableError{err}
 		}
+		head.Body.Close()
+		if head.Header.Get("Accept-Ranges") != "bytes" {
+			offset = 0
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
 	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return retryableError{err}
+	}
+	defer res.Body.Close()
+
+	resuming := offset > 0 && res.StatusCode == http.StatusPartialContent
+	switch {
+	case res.StatusCode == http.StatusOK, resuming:
+	case res.StatusCode >= 500:
+		return retryableError{fmt.Errorf("server error: %s", res.Status)}
+	default:
+		return fmt.Errorf("unexpected status: %s", res.Status)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	if resuming {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+	}
+
+	out, err := os.OpenFile(partial, flags, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, res.Body); err != nil {
+		out.Close()
+		return retryableError{err}
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(partial, dest)
 }
@@ -0,0 +1,238 @@
+package main
+
+import "time"
+
+// coalesceWindow is how close together two Coalesce-able commands have to
+// occur to be merged into one undo step, so that dragging a tile across
+// the grid collapses into a single Ctrl+Z rather than one per tick.
+const coalesceWindow = 500 * time.Millisecond
+
+// undoCapacity bounds the ring buffer so a long session can't grow the
+// undo history without limit.
+const undoCapacity = 200
+
+// Command is one undoable, redoable mutation of the Game's tile or menu
+// state.
+type Command interface {
+	Apply(g *Game)
+	Revert(g *Game)
+	// Coalesce reports whether other represents a continuation of this
+	// command (e.g. the same drag, one tick later). If so it updates
+	// the receiver in place to also cover other, and the caller must
+	// not push other separately.
+	Coalesce(other Command) bool
+}
+
+// UndoStack is a command-pattern undo/redo history backed by a bounded
+// ring buffer. Pushing a new command always clears the redo stack.
+type UndoStack struct {
+	entries []Command
+	start   int
+	size    int
+	redo    []Command
+}
+
+// NewUndoStack returns an empty UndoStack capped at undoCapacity entries.
+func NewUndoStack() *UndoStack {
+	return &UndoStack{entries: make([]Command, undoCapacity)}
+}
+
+// Push applies cmd to g and records it, coalescing it into the most
+// recent command when possible. Any pending redo history is discarded,
+// since it no longer follows from the current state.
+func (s *UndoStack) Push(g *Game, cmd Command) {
+	cmd.Apply(g)
+	s.redo = s.redo[:0]
+
+	if s.size > 0 {
+		last := s.entries[(s.start+s.size-1)%undoCapacity]
+		if last.Coalesce(cmd) {
+			return
+		}
+	}
+
+	if s.size == undoCapacity {
+		s.start = (s.start + 1) % undoCapacity
+	} else {
+		s.size++
+	}
+	s.entries[(s.start+s.size-1)%undoCapacity] = cmd
+}
+
+// Undo reverts the most recently applied command, if any, moving it onto
+// the redo stack.
+func (s *UndoStack) Undo(g *Game) bool {
+	if s.size == 0 {
+		return false
+	}
+
+	idx := (s.start + s.size - 1) % undoCapacity
+	cmd := s.entries[idx]
+	cmd.Revert(g)
+	s.size--
+	s.redo = append(s.redo, cmd)
+	return true
+}
+
+// Redo re-applies the most recently undone command, if any.
+func (s *UndoStack) Redo(g *Game) bool {
+	if len(s.redo) == 0 {
+		return false
+	}
+
+	cmd := s.redo[len(s.redo)-1]
+	s.redo = s.redo[:len(s.redo)-1]
+	cmd.Apply(g)
+
+	if s.size == undoCapacity {
+		s.start = (s.start + 1) % undoCapacity
+	} else {
+		s.size++
+	}
+	s.entries[(s.start+s.size-1)%undoCapacity] = cmd
+	return true
+}
+
+// PlaceCommand records dropping a new tile onto the grid.
+type PlaceCommand struct {
+	Tile *Tile
+}
+
+func (c *PlaceCommand) Apply(g *Game)         { g.Tiles = append(g.Tiles, c.Tile) }
+func (c *PlaceCommand) Revert(g *Game)        { g.Tiles = removeTile(g.Tiles, c.Tile) }
+func (c *PlaceCommand) Coalesce(Command) bool { return false }
+
+// MoveCommand records an existing tile moving from one grid position to
+// another.
+type MoveCommand struct {
+	Tile         *Tile
+	FromX, FromY int
+	ToX, ToY     int
+	At           time.Time
+}
+
+func (c *MoveCommand) Apply(g *Game) {
+	c.Tile.X, c.Tile.Y = c.ToX, c.ToY
+}
+
+func (c *MoveCommand) Revert(g *Game) {
+	c.Tile.X, c.Tile.Y = c.FromX, c.FromY
+}
+
+func (c *MoveCommand) Coalesce(other Command) bool {
+	o, ok := other.(*MoveCommand)
+	if !ok || o.Tile != c.Tile || o.At.Sub(c.At) > coalesceWindow {
+		return false
+	}
+	c.ToX, c.ToY = o.ToX, o.ToY
+	c.At = o.At
+	return true
+}
+
+// MenuMoveCommand records the floating menu being dragged to a new
+// position.
+type MenuMoveCommand struct {
+	Menu         *Menu
+	FromX, FromY int
+	ToX, ToY     int
+	At           time.Time
+}
+
+func (c *MenuMoveCommand) Apply(g *Game) {
+	c.Menu.X, c.Menu.Y = c.ToX, c.ToY
+}
+
+func (c *MenuMoveCommand) Revert(g *Game) {
+	c.Menu.X, c.Menu.Y = c.FromX, c.FromY
+}
+
+func (c *MenuMoveCommand) Coalesce(other Command) bool {
+	o, ok := other.(*MenuMoveCommand)
+	if !ok || o.Menu != c.Menu || o.At.Sub(c.At) > coalesceWindow {
+		return false
+	}
+	c.ToX, c.ToY = o.ToX, o.ToY
+	c.At = o.At
+	return true
+}
+
+// GroupMoveCommand records a multi-tile selection moving together,
+// preserving each tile's offset from the others.
+type GroupMoveCommand struct {
+	Tiles        []*Tile
+	FromX, FromY []int
+	ToX, ToY     []int
+	At           time.Time
+}
+
+func (c *GroupMoveCommand) Apply(g *Game) {
+	for i, t := range c.Tiles {
+		t.X, t.Y = c.ToX[i], c.ToY[i]
+	}
+}
+
+func (c *GroupMoveCommand) Revert(g *Game) {
+	for i, t := range c.Tiles {
+		t.X, t.Y = c.FromX[i], c.FromY[i]
+	}
+}
+
+func (c *GroupMoveCommand) Coalesce(other Command) bool {
+	o, ok := other.(*GroupMoveCommand)
+	if !ok || o.At.Sub(c.At) > coalesceWindow || len(o.Tiles) != len(c.Tiles) {
+		return false
+	}
+	for i := range c.Tiles {
+		if o.Tiles[i] != c.Tiles[i] {
+			return false
+		}
+	}
+	c.ToX, c.ToY = o.ToX, o.ToY
+	c.At = o.At
+	return true
+}
+
+// DeleteCommand records removing a set of tiles, usually the current
+// selection, from the grid.
+type DeleteCommand struct {
+	Tiles []*Tile
+}
+
+func (c *DeleteCommand) Apply(g *Game) {
+	for _, t := range c.Tiles {
+		g.Tiles = removeTile(g.Tiles, t)
+	}
+	g.Selected = nil
+}
+
+func (c *DeleteCommand) Revert(g *Game) {
+	g.Tiles = append(g.Tiles, c.Tiles...)
+	g.Selected = append([]*Tile{}, c.Tiles...)
+}
+
+func (c *DeleteCommand) Coalesce(Command) bool { return false }
+
+// PasteCommand records dropping a pasted group of tiles onto the grid.
+type PasteCommand struct {
+	Tiles []*Tile
+}
+
+func (c *PasteCommand) Apply(g *Game) { g.Tiles = append(g.Tiles, c.Tiles...) }
+
+func (c *PasteCommand) Revert(g *Game) {
+	for _, t := range c.Tiles {
+		g.Tiles = removeTile(g.Tiles, t)
+	}
+}
+
+func (c *PasteCommand) Coalesce(Command) bool { return false }
+
+// removeTile returns tiles with t removed, preserving order.
+func removeTile(tiles []*Tile, t *Tile) []*Tile {
+	for i, candidate := range tiles {
+		if candidate == t {
+			return append(tiles[:i], tiles[i+1:]...)
+		}
+	}
+	return tiles
+}
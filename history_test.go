@@ -0,0 +1,114 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+type noopCommand struct{ applied, reverted int }
+
+func (c *noopCommand) Apply(g *Game)         { c.applied++ }
+func (c *noopCommand) Revert(g *Game)        { c.reverted++ }
+func (c *noopCommand) Coalesce(Command) bool { return false }
+
+func TestUndoStackPushUndoRedo(t *testing.T) {
+	s := NewUndoStack()
+	g := &Game{}
+
+	a := &noopCommand{}
+	b := &noopCommand{}
+	s.Push(g, a)
+	s.Push(g, b)
+
+	if a.applied != 1 || b.applied != 1 {
+		t.Fatalf("Push should Apply each command once")
+	}
+
+	if !s.Undo(g) || b.reverted != 1 {
+		t.Fatalf("Undo should Revert the most recently pushed command")
+	}
+	if !s.Undo(g) || a.reverted != 1 {
+		t.Fatalf("Undo should Revert commands in LIFO order")
+	}
+	if s.Undo(g) {
+		t.Fatalf("Undo on an empty stack should report false")
+	}
+
+	if !s.Redo(g) || a.applied != 2 {
+		t.Fatalf("Redo should re-Apply the most recently undone command")
+	}
+	if !s.Redo(g) || b.applied != 2 {
+		t.Fatalf("Redo should replay commands in the reverse of the order they were undone")
+	}
+	if s.Redo(g) {
+		t.Fatalf("Redo with nothing undone should report false")
+	}
+}
+
+func TestUndoStackPushClearsRedo(t *testing.T) {
+	s := NewUndoStack()
+	g := &Game{}
+
+	s.Push(g, &noopCommand{})
+	s.Undo(g)
+	s.Push(g, &noopCommand{})
+
+	if s.Redo(g) {
+		t.Fatalf("pushing a new command should clear the redo stack")
+	}
+}
+
+func TestMoveCommandCoalesce(t *testing.T) {
+	s := NewUndoStack()
+	g := &Game{}
+	tile := &Tile{X: 0, Y: 0}
+
+	start := time.Now()
+	s.Push(g, &MoveCommand{Tile: tile, FromX: 0, FromY: 0, ToX: 32, ToY: 0, At: start})
+	s.Push(g, &MoveCommand{Tile: tile, FromX: 32, FromY: 0, ToX: 64, ToY: 0, At: start.Add(10 * time.Millisecond)})
+
+	if s.size != 1 {
+		t.Fatalf("size = %d, want 1 (the second move should coalesce into the first)", s.size)
+	}
+
+	s.Undo(g)
+	if tile.X != 0 {
+		t.Fatalf("tile.X = %d after undo, want 0 (coalesced move should revert to the original position)", tile.X)
+	}
+}
+
+func TestMoveCommandDoesNotCoalesceAcrossWindow(t *testing.T) {
+	s := NewUndoStack()
+	g := &Game{}
+	tile := &Tile{X: 0, Y: 0}
+
+	start := time.Now()
+	s.Push(g, &MoveCommand{Tile: tile, FromX: 0, FromY: 0, ToX: 32, ToY: 0, At: start})
+	s.Push(g, &MoveCommand{Tile: tile, FromX: 32, FromY: 0, ToX: 64, ToY: 0, At: start.Add(2 * coalesceWindow)})
+
+	if s.size != 2 {
+		t.Fatalf("size = %d, want 2 (moves outside the coalesce window should not merge)", s.size)
+	}
+}
+
+func TestUndoStackWraparound(t *testing.T) {
+	s := NewUndoStack()
+	g := &Game{}
+
+	for i := 0; i < undoCapacity+10; i++ {
+		s.Push(g, &PlaceCommand{Tile: &Tile{}})
+	}
+
+	if s.size != undoCapacity {
+		t.Fatalf("size = %d, want %d (ring buffer should cap at its capacity)", s.size, undoCapacity)
+	}
+
+	for i := 0; i < undoCapacity; i++ {
+		if !s.Undo(g) {
+			t.Fatalf("Undo failed at iteration %d, want %d successful undos", i, undoCapacity)
+		}
+	}
+	if s.Undo(g) {
+		t.Fatalf("Undo should fail once the capped history is exhausted")
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// Action is an abstract input action. Game logic only ever reacts to
+// Actions, never to raw ebiten keys, so keymap.json can rebind any of
+// them without touching game code.
+type Action string
+
+const (
+	ActionPrevPage       Action = "prev_page"
+	ActionNextPage       Action = "next_page"
+	ActionRotateLeft     Action = "rotate_left"
+	ActionRotateRight    Action = "rotate_right"
+	ActionUndo           Action = "undo"
+	ActionRedo           Action = "redo"
+	ActionSave           Action = "save"
+	ActionLoad           Action = "load"
+	ActionExportPDF      Action = "export_pdf"
+	ActionToggleDropdown Action = "toggle_dropdown"
+	ActionTogglePathMode Action = "toggle_path_mode"
+	ActionToggleWalkable Action = "toggle_walkable_overlay"
+	ActionDelete         Action = "delete"
+	ActionCopy           Action = "copy"
+	ActionPaste          Action = "paste"
+)
+
+// InputEventKind distinguishes the cases of the InputEvent sum type.
+type InputEventKind int
+
+const (
+	MouseDown InputEventKind = iota
+	MouseUp
+	MouseMove
+	KeyPress
+)
+
+// InputEvent is a single input occurrence, already translated out of
+// ebiten's polling API. A stream of these can be constructed by hand,
+// which is what makes Game.Advance exercisable without a real window.
+type InputEvent struct {
+	Kind   InputEventKind
+	X, Y   int                // set for MouseDown, MouseUp, MouseMove
+	Button ebiten.MouseButton // set for MouseDown, MouseUp
+	Shift  bool               // set for MouseDown, MouseUp: was shift held
+	Action Action             // set for KeyPress, already resolved via a Keymap
+}
+
+// binding is one (key, modifiers) chord a Keymap can map to an Action.
+type binding struct {
+	Key   ebiten.Key
+	Shift bool
+	Ctrl  bool
+}
+
+// Keymap maps raw key chords to abstract Actions. Mouse clicks are not
+// remapped here: the menu and grid are hit-tested by position, so only
+// keyboard shortcuts are pluggable.
+type Keymap struct {
+	Bindings map[binding]Action
+}
+
+// DefaultKeymap returns the keymap the game ships with.
+func DefaultKeymap() Keymap {
+	return Keymap{Bindings: map[binding]Action{
+		{Key: ebiten.KeyPageUp}:         ActionPrevPage,
+		{Key: ebiten.KeyPageDown}:       ActionNextPage,
+		{Key: ebiten.KeyR}:              ActionRotateRight,
+		{Key: ebiten.KeyR, Shift: true}: ActionRotateLeft,
+		{Key: ebiten.KeyTab}:            ActionToggleDropdown,
+		{Key: ebiten.KeyZ, Ctrl: true}:  ActionUndo,
+		{Key: ebiten.KeyY, Ctrl: true}:  ActionRedo,
+		{Key: ebiten.KeyS, Ctrl: true}:  ActionSave,
+		{Key: ebiten.KeyL, Ctrl: true}:  ActionLoad,
+		{Key: ebiten.KeyP, Ctrl: true}:  ActionExportPDF,
+		{Key: ebiten.KeyP}:              ActionTogglePathMode,
+		{Key: ebiten.KeyO}:              ActionToggleWalkable,
+		{Key: ebiten.KeyDelete}:         ActionDelete,
+		{Key: ebiten.KeyC, Ctrl: true}:  ActionCopy,
+		{Key: ebiten.KeyV, Ctrl: true}:  ActionPaste,
+	}}
+}
+
+// keyNames maps the JSON key names accepted in keymap.json to ebiten
+// keys. Only the keys the default bindings use are listed; extend this
+// table as new actions need new keys.
+var keyNames = map[string]ebiten.Key{
+	"PageUp":   ebiten.KeyPageUp,
+	"PageDown": ebiten.KeyPageDown,
+	"R":        ebiten.KeyR,
+	"Tab":      ebiten.KeyTab,
+	"Z":        ebiten.KeyZ,
+	"Y":        ebiten.KeyY,
+	"S":        ebiten.KeyS,
+	"L":        ebiten.KeyL,
+	"P":        ebiten.KeyP,
+	"O":        ebiten.KeyO,
+	"Delete":   ebiten.KeyDelete,
+	"C":        ebiten.KeyC,
+	"V":        ebiten.KeyV,
+}
+
+// keymapEntry is one line of keymap.json: a key name, optional
+// modifiers, and the action it should trigger.
+type keymapEntry struct {
+	Key    string `json:"key"`
+	Shift  bool   `json:"shift,omitempty"`
+	Ctrl   bool   `json:"ctrl,omitempty"`
+	Action string `json:"action"`
+}
+
+// LoadKeymap reads path as a JSON array of keymapEntry and returns the
+// resulting Keymap. A missing file is not an error: it just means the
+// default bindings apply.
+func LoadKeymap(path string) (Keymap, error) {
+	km := DefaultKeymap()
+
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return km, nil
+		}
+		return km, err
+	}
+
+	var entries []keymapEntry
+	if err := json.Unmarshal(buf, &entries); err != nil {
+		return km, err
+	}
+
+	for _, e := range entries {
+		key, ok := keyNames[e.Key]
+		if !ok {
+			return km, fmt.Errorf("keymap: unknown key %q", e.Key)
+		}
+		km.Bindings[binding{Key: key, Shift: e.Shift, Ctrl: e.Ctrl}] = Action(e.Action)
+	}
+
+	return km, nil
+}
+
+// PollEvents translates this tick's raw ebiten input into InputEvents,
+// resolving key chords into Actions via km.
+func PollEvents(km Keymap) []InputEvent {
+	var events []InputEvent
+
+	x, y := ebiten.CursorPosition()
+	shift := ebiten.IsKeyPressed(ebiten.KeyShiftLeft) || ebiten.IsKeyPressed(ebiten.KeyShiftRight)
+	ctrl := ebiten.IsKeyPressed(ebiten.KeyControlLeft) || ebiten.IsKeyPressed(ebiten.KeyControlRight)
+
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		events = append(events, InputEvent{Kind: MouseDown, X: x, Y: y, Button: ebiten.MouseButtonLeft, Shift: shift})
+	}
+	if inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) {
+		events = append(events, InputEvent{Kind: MouseUp, X: x, Y: y, Button: ebiten.MouseButtonLeft, Shift: shift})
+	}
+
+	for b, action := range km.Bindings {
+		if b.Shift != shift || b.Ctrl != ctrl {
+			continue
+		}
+		if inpututil.IsKeyJustPressed(b.Key) {
+			events = append(events, InputEvent{Kind: KeyPress, Action: action})
+		}
+	}
+
+	return events
+}
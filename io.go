@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+const (
+	projectPath  = "project.json"
+	autosavePath = "autosave.json"
+
+	logicalTPS         = 60
+	autosaveEveryTicks = 30 * logicalTPS
+
+	// pdfPageWidth and pdfPageHeight are one US-letter page at 72dpi, in
+	// source pixels. ExportPDF tiles the screen across as many pages as
+	// it takes to cover it, rather than shrinking everything onto one.
+	pdfPageWidth  = 612
+	pdfPageHeight = 792
+)
+
+// Project is the on-disk representation of a Game. It stores grid
+// coordinates rather than pixel coordinates so that it survives changes to
+// the tile size, and it stores tile identity (set/name/orientation) rather
+// than image data so that it can be re-hydrated against whatever tile set
+// is loaded at load time.
+type Project struct {
+	GridWidth  int          `json:"gridWidth"`
+	GridHeight int          `json:"gridHeight"`
+	MenuX      int          `json:"menuX"`
+	MenuY      int          `json:"menuY"`
+	Tiles      []PlacedTile `json:"tiles"`
+}
+
+// PlacedTile identifies one tile placed on the grid.
+type PlacedTile struct {
+	Set         string `json:"set"`
+	Name        string `json:"name"`
+	Orientation int    `json:"orientation"`
+	GridX       int    `json:"gridX"`
+	GridY       int    `json:"gridY"`
+}
+
+// SaveProject serializes the current game state to path as JSON.
+func SaveProject(g *Game, path string) error {
+	p := Project{
+		GridWidth:  g.Grid.X,
+		GridHeight: g.Grid.Y,
+		MenuX:      g.Menu.X,
+		MenuY:      g.Menu.Y,
+	}
+
+	for _, t := range g.Tiles {
+		p.Tiles = append(p.Tiles, PlacedTile{
+			Set:         t.Set,
+			Name:        t.Name,
+			Orientation: t.Orientation,
+			GridX:       t.X / 32,
+			GridY:       t.Y / 32,
+		})
+	}
+
+	buf, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf, 0644)
+}
+
+// LoadProject reads path and rebuilds g.Tiles from it, looking each tile up
+// in g.Library.Sets by (Set, Name, Orientation).
+func LoadProject(g *Game, path string) error {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var p Project
+	if err := json.Unmarshal(buf, &p); err != nil {
+		return err
+	}
+
+	tiles := make([]*Tile, 0, len(p.Tiles))
+	for _, pt := range p.Tiles {
+		set, ok := g.Library.Sets[pt.Set]
+		if !ok {
+			return fmt.Errorf("load project: unknown set %q", pt.Set)
+		}
+
+		orientations, ok := set.Values[pt.Name]
+		if !ok {
+			return fmt.Errorf("load project: unknown tile %q in set %q", pt.Name, pt.Set)
+		}
+
+		libTile, ok := orientations[pt.Orientation]
+		if !ok {
+			return fmt.Errorf("load project: tile %q has no orientation %d", pt.Name, pt.Orientation)
+		}
+
+		tiles = append(tiles, &Tile{
+			X:           pt.GridX * 32,
+			Y:           pt.GridY * 32,
+			Name:        pt.Name,
+			Set:         pt.Set,
+			Size:        libTile.Size,
+			Orientation: pt.Orientation,
+			Image:       libTile.Image,
+			Walkable:    libTile.Walkable,
+		})
+	}
+
+	g.Grid.X = p.GridWidth
+	g.Grid.Y = p.GridHeight
+	g.Menu.X = p.MenuX
+	g.Menu.Y = p.MenuY
+	g.Tiles = tiles
+
+	return nil
+}
+
+// ExportPNG writes screen to path as a single PNG, the same format the
+// save button has always produced.
+func ExportPNG(screen *ebiten.Image, path string) error {
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, screen); err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, buf)
+	return err
+}
+
+// pdfPage is one page's worth of raw, uncompressed RGB samples, ready to
+// embed in a PDF image XObject.
+type pdfPage struct {
+	Width, Height int
+	RGB           []byte
+}
+
+// ExportPDF writes screen to path as a multi-page PDF for printing,
+// tiling it across as many letter-sized pages as its dimensions require
+// rather than squeezing the whole map onto a single page. It walks the
+// same screen image ExportPNG does, just a page at a time.
+func ExportPDF(screen *ebiten.Image, path string) error {
+	bounds := screen.Bounds()
+
+	var pages []pdfPage
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += pdfPageHeight {
+		h := Min(pdfPageHeight, bounds.Max.Y-y)
+		for x := bounds.Min.X; x < bounds.Max.X; x += pdfPageWidth {
+			w := Min(pdfPageWidth, bounds.Max.X-x)
+			pages = append(pages, pdfPage{Width: w, Height: h, RGB: rgbSamples(screen, x, y, w, h)})
+		}
+	}
+
+	return os.WriteFile(path, buildPDF(pages), 0644)
+}
+
+// rgbSamples reads the w-by-h block of screen starting at (x, y) into a
+// flat, row-major RGB byte slice, dropping alpha: PDF's uncompressed
+// image XObjects have no concept of transparency.
+func rgbSamples(screen *ebiten.Image, x, y, w, h int) []byte {
+	out := make([]byte, 0, w*h*3)
+	for row := 0; row < h; row++ {
+		for col := 0; col < w; col++ {
+			r, g, b, _ := screen.At(x+col, y+row).RGBA()
+			out = append(out, byte(r>>8), byte(g>>8), byte(b>>8))
+		}
+	}
+	return out
+}
+
+// buildPDF assembles pages into a minimal, dependency-free PDF: one Page
+// and one uncompressed DeviceRGB image XObject per entry, linked by a
+// hand-written cross-reference table. There's no PDF library in this
+// tree to reach for, so this only implements the handful of object types
+// a page of flat RGB samples actually needs.
+func buildPDF(pages []pdfPage) []byte {
+	var buf bytes.Buffer
+	offsets := []int{0} // object 0 is the free-list head; objects are 1-indexed
+
+	startObj := func() {
+		offsets = append(offsets, buf.Len())
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	startObj()
+	buf.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+
+	kids := new(bytes.Buffer)
+	for i := range pages {
+		fmt.Fprintf(kids, "%d 0 R ", 3+3*i)
+	}
+	startObj()
+	fmt.Fprintf(&buf, "2 0 obj\n<< /Type /Pages /Kids [ %s] /Count %d >>\nendobj\n", kids.String(), len(pages))
+
+	for i, p := range pages {
+		pageObj, contentObj, imageObj := 3+3*i, 4+3*i, 5+3*i
+
+		content := fmt.Sprintf("q %d 0 0 %d 0 0 cm /Im%d Do Q", p.Width, p.Height, i)
+
+		startObj()
+		fmt.Fprintf(&buf, "%d 0 obj\n<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] "+
+			"/Resources << /XObject << /Im%d %d 0 R >> >> /Contents %d 0 R >>\nendobj\n",
+			pageObj, p.Width, p.Height, i, imageObj, contentObj)
+
+		startObj()
+		fmt.Fprintf(&buf, "%d 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n",
+			contentObj, len(content), content)
+
+		startObj()
+		fmt.Fprintf(&buf, "%d 0 obj\n<< /Type /XObject /Subtype /Image /Width %d /Height %d "+
+			"/ColorSpace /DeviceRGB /BitsPerComponent 8 /Length %d >>\nstream\n",
+			imageObj, p.Width, p.Height, len(p.RGB))
+		buf.Write(p.RGB)
+		buf.WriteString("\nendstream\nendobj\n")
+	}
+
+	xrefStart := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n", len(offsets))
+	buf.WriteString("0000000000 65535 f \n")
+	for _, off := range offsets[1:] {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF", len(offsets), xrefStart)
+
+	return buf.Bytes()
+}
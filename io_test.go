@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestBuildPDFPagesPerObject(t *testing.T) {
+	pages := []pdfPage{
+		{Width: 10, Height: 20, RGB: make([]byte, 10*20*3)},
+		{Width: 5, Height: 5, RGB: make([]byte, 5*5*3)},
+	}
+
+	buf := buildPDF(pages)
+
+	if !bytes.HasPrefix(buf, []byte("%PDF-1.4\n")) {
+		t.Fatalf("buildPDF output does not start with a PDF header")
+	}
+	if !bytes.Contains(buf, []byte("/Count 2")) {
+		t.Fatalf("expected /Count 2 for a 2-page document")
+	}
+	if n := bytes.Count(buf, []byte("/Subtype /Image")); n != len(pages) {
+		t.Fatalf("got %d image XObjects, want %d", n, len(pages))
+	}
+	if !bytes.HasSuffix(bytes.TrimRight(buf, "\n"), []byte("%%EOF")) {
+		t.Fatalf("buildPDF output does not end with %%%%EOF")
+	}
+}
+
+func TestBuildPDFEmpty(t *testing.T) {
+	buf := buildPDF(nil)
+
+	if !bytes.Contains(buf, []byte("/Count 0")) {
+		t.Fatalf("expected /Count 0 for a document with no pages")
+	}
+}
@@ -1,22 +1,21 @@
 package main
 
 import (
-	"bytes"
 	"image"
 	"image/color"
 	_ "image/gif"
 	_ "image/jpeg"
-	"image/png"
 	"io"
+	"io/fs"
 	"log"
 	"os"
 	"regexp"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
-	"github.com/hajimehoshi/ebiten/v2/inpututil"
 	"github.com/hajimehoshi/ebiten/v2/text"
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/gofont/goregular"
@@ -37,18 +36,43 @@ const (
 	sizeOffset           = 448
 	saveOffsetX          = 32
 	saveOffsetY          = 448
+	loadOffsetX          = 64
+	loadOffsetY          = 448
+	undoOffsetX          = 96
+	undoOffsetY          = 448
+	redoOffsetX          = 128
+	redoOffsetY          = 448
+	pathOffsetX          = 160
+	pathOffsetY          = 448
+	walkableOffsetX      = 192
+	walkableOffsetY      = 448
 )
 
 var tileNameRE = regexp.MustCompile(`^(DT\d)_(\d{1,2}x\d{1,2}){0,1}_{0,1}(\w?\.?\w+\.[a|b])\.(0|90|180|270)\.(?:png|jpg|gif)$`)
 
 type Game struct {
-	CursorDrag *CursorDrag
-	Library    Library   // The set of available tiles
-	Tiles      []*Tile   // The tiles placed on the grid
-	Menu       Menu      // The menu with all options
-	Grid       Grid      // The grid where tiles are placed
-	Font       font.Face // The font the menu is rendered with
-	Save       bool
+	CursorDrag   *CursorDrag
+	Library      Library   // The set of available tiles
+	Tiles        []*Tile   // The tiles placed on the grid
+	Menu         Menu      // The menu with all options
+	Grid         Grid      // The grid where tiles are placed
+	Font         font.Face // The font the menu is rendered with
+	Save         bool
+	Load         bool
+	ExportPDF    bool
+	Ticks        int64 // logical ticks elapsed, advanced once per Update call
+	History      *UndoStack
+	Keymap       Keymap
+	PathMode     bool // whether a click picks path endpoints instead of placing or dragging
+	PathStart    *image.Point
+	PathPreview  []image.Point
+	ShowWalkable bool
+	Selected     []*Tile          // tiles in the current multi-selection
+	GroupDrag    *CursorDragGroup // an in-progress drag of the whole selection
+	SelectOrigin *image.Point     // origin of an in-progress rubber-band select
+	Clipboard    []*Tile          // tiles captured by the last copy
+	Paste        []*Tile          // tiles following the cursor after a paste, until placed
+	PasteOffsets []image.Point    // each Paste tile's offset, in cells, from Paste[0]
 }
 
 type Menu struct {
@@ -62,6 +86,11 @@ type Menu struct {
 	RotateLeft          *ebiten.Image
 	RotateRight         *ebiten.Image
 	SaveButton          *ebiten.Image
+	LoadButton          *ebiten.Image
+	UndoButton          *ebiten.Image
+	RedoButton          *ebiten.Image
+	PathButton          *ebiten.Image
+	WalkableButton      *ebiten.Image
 	SelectedSet         string
 	SelectedTile        string
 	SelectedOrientation int
@@ -95,11 +124,14 @@ type Grid struct {
 }
 
 type Tile struct {
-	X     int
-	Y     int
-	Name  string
-	Size  string
-	Image *ebiten.Image
+	X           int
+	Y           int
+	Name        string
+	Set         string
+	Size        string
+	Orientation int
+	Image       *ebiten.Image
+	Walkable    [][]bool // per-cell walkability mask, row-major; nil means fully walkable
 }
 
 func (t *Tile) GetX() int {
@@ -123,9 +155,11 @@ func (t *Tile) GetImage() *ebiten.Image {
 }
 
 type CursorDrag struct {
-	Origin    image.Point
-	Target    CursorTarget
-	IsNewTile bool
+	Origin        image.Point
+	Target        CursorTarget
+	IsNewTile     bool
+	OriginTargetX int
+	OriginTargetY int
 }
 
 type CursorTarget interface {
@@ -136,184 +170,545 @@ type CursorTarget interface {
 	GetImage() *ebiten.Image
 }
 
+// CursorDragGroup is an in-progress drag of every tile in the current
+// selection, preserving each tile's offset from the others.
+type CursorDragGroup struct {
+	Origin       image.Point
+	Tiles        []*Tile
+	FromX, FromY []int
+}
+
 func IsPointInRect(x, y int, r image.Rectangle) bool {
 	return r.Min.X < x && x < r.Max.X && r.Min.Y < y && y < r.Max.Y
 }
 
+// tileSelected reports whether t is a member of selected.
+func tileSelected(selected []*Tile, t *Tile) bool {
+	for _, s := range selected {
+		if s == t {
+			return true
+		}
+	}
+	return false
+}
+
+// toggleTileSelection adds t to selected, or removes it if already
+// present.
+func toggleTileSelection(selected []*Tile, t *Tile) []*Tile {
+	for i, s := range selected {
+		if s == t {
+			return append(selected[:i], selected[i+1:]...)
+		}
+	}
+	return append(selected, t)
+}
+
+// Update translates raw ebiten input into InputEvents via g.Keymap and
+// hands them to Advance. It does not itself mutate any game state.
 func (g *Game) Update() error {
-	if inpututil.IsKeyJustReleased(ebiten.KeyPageUp) {
+	events := PollEvents(g.Keymap)
+
+	if dropped := ebiten.DroppedFiles(); dropped != nil {
+		names, err := fs.Glob(dropped, "*")
+		if err == nil && len(names) > 0 {
+			if f, err := dropped.Open(names[0]); err == nil {
+				if buf, err := io.ReadAll(f); err == nil {
+					if err := os.WriteFile(projectPath, buf, 0644); err == nil {
+						events = append(events, InputEvent{Kind: KeyPress, Action: ActionLoad})
+					}
+				}
+				f.Close()
+			}
+		}
+	}
+
+	return g.Advance(events)
+}
+
+// Advance runs one logical tick of game-state mutation: it ticks the
+// autosave timer, moves any pending paste along with the cursor, and
+// dispatches this tick's InputEvents, which is where undo commands are
+// recorded. Save, load and PDF export only set flags that Draw acts
+// on. It has no hover animation. It is called once per Update
+// at ebiten's fixed logical tick rate (60 TPS by default), independent
+// of how often Draw renders a frame.
+func (g *Game) Advance(events []InputEvent) error {
+	g.Ticks++
+	if g.Ticks%autosaveEveryTicks == 0 {
+		if err := SaveProject(g, autosavePath); err != nil {
+			log.Println("autosave:", err)
+		}
+	}
+
+	if g.Paste != nil {
+		x, y := ebiten.CursorPosition()
+		baseX, baseY := x-x%32, y-y%32
+		for i, t := range g.Paste {
+			t.X = baseX + g.PasteOffsets[i].X*32
+			t.Y = baseY + g.PasteOffsets[i].Y*32
+		}
+	}
+
+	for _, e := range events {
+		switch e.Kind {
+		case KeyPress:
+			g.handleAction(e.Action)
+		case MouseDown:
+			g.handleMouseDown(e.X, e.Y, e.Shift)
+		case MouseUp:
+			g.handleMouseUp(e.X, e.Y, e.Shift)
+		}
+	}
+
+	return nil
+}
+
+// rotate adjusts the menu's selected tile orientation by delta degrees,
+// wrapping into [0, 360).
+func (g *Game) rotate(delta int) {
+	g.Menu.SelectedOrientation = ((g.Menu.SelectedOrientation+delta)%360 + 360) % 360
+}
+
+// handleAction runs the game-state change for one resolved Action.
+func (g *Game) handleAction(a Action) {
+	switch a {
+	case ActionPrevPage:
 		if g.Menu.Page > 0 {
 			g.Menu.Page--
 		}
+	case ActionNextPage:
+		g.Menu.Page++
+	case ActionRotateLeft:
+		g.rotate(-90)
+	case ActionRotateRight:
+		g.rotate(90)
+	case ActionUndo:
+		g.History.Undo(g)
+	case ActionRedo:
+		g.History.Redo(g)
+	case ActionSave:
+		g.Save = true
+	case ActionLoad:
+		g.Load = true
+	case ActionExportPDF:
+		g.ExportPDF = true
+	case ActionToggleDropdown:
+		g.Menu.IsOpen = !g.Menu.IsOpen
+	case ActionTogglePathMode:
+		g.PathMode = !g.PathMode
+		g.PathStart = nil
+		g.PathPreview = nil
+	case ActionToggleWalkable:
+		g.ShowWalkable = !g.ShowWalkable
+	case ActionDelete:
+		if len(g.Selected) > 0 {
+			g.History.Push(g, &DeleteCommand{Tiles: append([]*Tile{}, g.Selected...)})
+		}
+	case ActionCopy:
+		if len(g.Selected) > 0 {
+			g.Clipboard = make([]*Tile, len(g.Selected))
+			for i, t := range g.Selected {
+				g.Clipboard[i] = &Tile{
+					Name: t.Name, Set: t.Set, Orientation: t.Orientation,
+					Image: t.Image, Walkable: t.Walkable, X: t.X, Y: t.Y,
+				}
+			}
+		}
+	case ActionPaste:
+		if len(g.Clipboard) > 0 {
+			g.Paste = make([]*Tile, len(g.Clipboard))
+			g.PasteOffsets = make([]image.Point, len(g.Clipboard))
+			for i, t := range g.Clipboard {
+				g.Paste[i] = &Tile{
+					Name: t.Name, Set: t.Set, Orientation: t.Orientation,
+					Image: t.Image, Walkable: t.Walkable, X: t.X, Y: t.Y,
+				}
+				g.PasteOffsets[i] = image.Point{
+					X: (t.X - g.Clipboard[0].X) / 32,
+					Y: (t.Y - g.Clipboard[0].Y) / 32,
+				}
+			}
+		}
 	}
+}
 
-	if inpututil.IsKeyJustReleased(ebiten.KeyPageDown) {
-		g.Menu.Page++
+// handleMouseDown hit-tests a left-click at (x, y) against the menu
+// buttons, the dropdown, and the tiles, starting a CursorDrag or firing
+// the corresponding one-shot action. shift reports whether shift was held,
+// which toggles the clicked tile in or out of the selection instead of
+// replacing it.
+func (g *Game) handleMouseDown(x, y int, shift bool) {
+	if g.Paste != nil {
+		g.History.Push(g, &PasteCommand{Tiles: g.Paste})
+		g.Selected = append([]*Tile{}, g.Paste...)
+		g.Paste = nil
+		g.PasteOffsets = nil
+		return
 	}
 
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
-		x, y := ebiten.CursorPosition()
+	w, h := g.Menu.SaveButton.Size()
+	r := image.Rect(g.Menu.X+saveOffsetX, g.Menu.Y+saveOffsetY, g.Menu.X+w+saveOffsetX, g.Menu.Y+h+saveOffsetY)
+
+	if IsPointInRect(x, y, r) {
+		g.Save = true
+		return
+	}
+
+	w, h = g.Menu.LoadButton.Size()
+	r = image.Rect(g.Menu.X+loadOffsetX, g.Menu.Y+loadOffsetY, g.Menu.X+w+loadOffsetX, g.Menu.Y+h+loadOffsetY)
+
+	if IsPointInRect(x, y, r) {
+		g.Load = true
+		return
+	}
+
+	w, h = g.Menu.UndoButton.Size()
+	r = image.Rect(g.Menu.X+undoOffsetX, g.Menu.Y+undoOffsetY, g.Menu.X+w+undoOffsetX, g.Menu.Y+h+undoOffsetY)
+
+	if IsPointInRect(x, y, r) {
+		g.History.Undo(g)
+		return
+	}
 
-		w, h := g.Menu.SaveButton.Size()
-		r := image.Rect(g.Menu.X+saveOffsetX, g.Menu.Y+saveOffsetY, g.Menu.X+w+saveOffsetX, g.Menu.Y+h+saveOffsetY)
+	w, h = g.Menu.RedoButton.Size()
+	r = image.Rect(g.Menu.X+redoOffsetX, g.Menu.Y+redoOffsetY, g.Menu.X+w+redoOffsetX, g.Menu.Y+h+redoOffsetY)
 
-		if IsPointInRect(x, y, r) {
-			g.Save = true
-			return nil
+	if IsPointInRect(x, y, r) {
+		g.History.Redo(g)
+		return
+	}
+
+	w, h = g.Menu.PathButton.Size()
+	r = image.Rect(g.Menu.X+pathOffsetX, g.Menu.Y+pathOffsetY, g.Menu.X+w+pathOffsetX, g.Menu.Y+h+pathOffsetY)
+
+	if IsPointInRect(x, y, r) {
+		g.PathMode = !g.PathMode
+		g.PathStart = nil
+		g.PathPreview = nil
+		return
+	}
+
+	w, h = g.Menu.WalkableButton.Size()
+	r = image.Rect(g.Menu.X+walkableOffsetX, g.Menu.Y+walkableOffsetY, g.Menu.X+w+walkableOffsetX, g.Menu.Y+h+walkableOffsetY)
+
+	if IsPointInRect(x, y, r) {
+		g.ShowWalkable = !g.ShowWalkable
+		return
+	}
+
+	w, h = g.Menu.RotateLeft.Size()
+	r = image.Rect(g.Menu.X+rotateLeftOffsetX, g.Menu.Y+dropdownOffsetY, g.Menu.X+w+rotateLeftOffsetX, g.Menu.Y+h+dropdownOffsetY)
+
+	if IsPointInRect(x, y, r) {
+		g.rotate(-90)
+		return
+	}
+
+	w, h = g.Menu.RotateRight.Size()
+	r = image.Rect(g.Menu.X+rotateRightOffsetX, g.Menu.Y+dropdownOffsetY, g.Menu.X+w+rotateRightOffsetX, g.Menu.Y+h+dropdownOffsetY)
+
+	if IsPointInRect(x, y, r) {
+		g.rotate(90)
+		return
+	}
+
+	w, h = g.Menu.DropdownBar.Size()
+	r = image.Rect(g.Menu.X+dropdownBarOffsetX, g.Menu.Y+dropdownOffsetY, g.Menu.X+w+dropdownBarOffsetX, g.Menu.Y+h+dropdownOffsetY)
+
+	if IsPointInRect(x, y, r) {
+		// If the drowdown is clicked, open or close it
+		g.Menu.IsOpen = !g.Menu.IsOpen
+		return
+	}
+	// If menu is open, check for a click on a dropdown option
+	if g.Menu.IsOpen {
+		offset := 0
+
+		for i := g.Menu.Page * pageSize; i < g.Menu.Page*pageSize+pageSize && i < len(g.Library.Sets[g.Menu.SelectedSet].Keys); i++ {
+			offset += 32
+			r = image.Rect(g.Menu.X+dropdownBarOffsetX, g.Menu.Y+dropdownOffsetY+offset, g.Menu.X+w+dropdownBarOffsetX, g.Menu.Y+h+dropdownOffsetY+offset)
+			if IsPointInRect(x, y, r) {
+				g.Menu.SelectedTile = g.Library.Sets[g.Menu.SelectedSet].Keys[i]
+				break
+			}
 		}
+	}
 
-		w, h = g.Menu.RotateLeft.Size()
-		r = image.Rect(g.Menu.X+rotateLeftOffsetX, g.Menu.Y+dropdownOffsetY, g.Menu.X+w+rotateLeftOffsetX, g.Menu.Y+h+dropdownOffsetY)
+	// Whether an option was clicked or not, close the dropdown if the user clicked anywhere
+	g.Menu.IsOpen = false
 
-		if IsPointInRect(x, y, r) {
-			if g.Menu.SelectedOrientation == 0 {
-				g.Menu.SelectedOrientation = 270
+	// In path preview mode, clicks on the grid pick path endpoints instead
+	// of placing or dragging tiles.
+	if g.PathMode {
+		if cell, ok := g.cellAt(x, y); ok {
+			if g.PathStart == nil {
+				g.PathStart = &cell
+				g.PathPreview = nil
 			} else {
-				g.Menu.SelectedOrientation -= 90
+				g.PathPreview, _ = AStar(*g.PathStart, cell, g.Grid.X, g.Grid.Y, g.isWalkable)
+				g.PathStart = nil
 			}
-			return nil
 		}
+		return
+	}
 
-		w, h = g.Menu.RotateRight.Size()
-		r = image.Rect(g.Menu.X+rotateRightOffsetX, g.Menu.Y+dropdownOffsetY, g.Menu.X+w+rotateRightOffsetX, g.Menu.Y+h+dropdownOffsetY)
+	// If the user clicked on the menu header, set up a cursor drag
+	w, h = g.Menu.Header.Size()
+	r = image.Rect(g.Menu.X, g.Menu.Y, g.Menu.X+w, g.Menu.Y+h)
 
-		if IsPointInRect(x, y, r) {
-			g.Menu.SelectedOrientation = (g.Menu.SelectedOrientation + 90) % 360
-			return nil
+	if IsPointInRect(x, y, r) {
+		drag := &CursorDrag{
+			Origin:        image.Point{X: x, Y: y},
+			OriginTargetX: g.Menu.GetX(),
+			OriginTargetY: g.Menu.GetY(),
 		}
+		drag.Target = &g.Menu
+		g.CursorDrag = drag
+		return
+	}
 
-		w, h = g.Menu.DropdownBar.Size()
-		r = image.Rect(g.Menu.X+dropdownBarOffsetX, g.Menu.Y+dropdownOffsetY, g.Menu.X+w+dropdownBarOffsetX, g.Menu.Y+h+dropdownOffsetY)
-
-		if IsPointInRect(x, y, r) {
-			// If the drowdown is clicked, open or close it
-			g.Menu.IsOpen = !g.Menu.IsOpen
-			return nil
+	// If the user is not dragging the menu, check if they are dragging a new tile
+	w, h = g.Library.Sets[g.Menu.SelectedSet].Values[g.Menu.SelectedTile][g.Menu.SelectedOrientation].Image.Size()
+	r = image.Rect(g.Menu.X+dropdownBarOffsetX, g.Menu.Y+selectedTileOffsetY, g.Menu.X+dropdownBarOffsetX+w, g.Menu.Y+selectedTileOffsetY+h)
+	if IsPointInRect(x, y, r) {
+		drag := &CursorDrag{
+			Origin:    image.Point{X: x, Y: y},
+			IsNewTile: true,
 		}
-		// If menu is open, check for a click on a dropdown option
-		if g.Menu.IsOpen {
-			offset := 0
 
-			for i := g.Menu.Page * pageSize; i < g.Menu.Page*pageSize+pageSize && i < len(g.Library.Sets[g.Menu.SelectedSet].Keys); i++ {
-				offset += 32
-				r = image.Rect(g.Menu.X+dropdownBarOffsetX, g.Menu.Y+dropdownOffsetY+offset, g.Menu.X+w+dropdownBarOffsetX, g.Menu.Y+h+dropdownOffsetY+offset)
-				if IsPointInRect(x, y, r) {
-					g.Menu.SelectedTile = g.Library.Sets[g.Menu.SelectedSet].Keys[i]
-					break
-				}
-			}
+		snapX := x % 32
+		snapY := y % 32
+
+		selected := g.Library.Sets[g.Menu.SelectedSet].Values[g.Menu.SelectedTile][g.Menu.SelectedOrientation]
+		tile := Tile{
+			X:           x - snapX,
+			Y:           y - snapY,
+			Name:        g.Menu.SelectedTile,
+			Set:         g.Menu.SelectedSet,
+			Orientation: g.Menu.SelectedOrientation,
+			Image:       selected.Image,
+			Walkable:    selected.Walkable,
 		}
 
-		// Whether an option was clicked or not, close the dropdown if the user clicked anywhere
-		g.Menu.IsOpen = false
+		drag.Target = &tile
+		g.CursorDrag = drag
+		return
+	}
 
-		// If the user clicked on the menu header, set up a cursor drag
-		w, h = g.Menu.Header.Size()
-		r = image.Rect(g.Menu.X, g.Menu.Y, g.Menu.X+w, g.Menu.Y+h)
+	// If the user is not dragging the menu, check if they are dragging,
+	// selecting, or shift-selecting an existing tile
+	for i := 0; i < len(g.Tiles); i++ {
+		tile := g.Tiles[i]
+		w, h = tile.Image.Size()
+		r = image.Rect(tile.X, tile.Y, tile.X+w, tile.Y+h)
 
-		if IsPointInRect(x, y, r) {
-			drag := &CursorDrag{
-				Origin: image.Point{X: x, Y: y},
-			}
-			drag.Target = &g.Menu
-			g.CursorDrag = drag
-			return nil
-		}
-
-		// If the user is not dragging the menu, check if they are dragging a new tile
-		w, h = g.Library.Sets[g.Menu.SelectedSet].Values[g.Menu.SelectedTile][g.Menu.SelectedOrientation].Image.Size()
-		r = image.Rect(g.Menu.X+dropdownBarOffsetX, g.Menu.Y+selectedTileOffsetY, g.Menu.X+dropdownBarOffsetX+w, g.Menu.Y+selectedTileOffsetY+h)
-		if IsPointInRect(x, y, r) {
-			drag := &CursorDrag{
-				Origin:    image.Point{X: x, Y: y},
-				IsNewTile: true,
-			}
+		if !IsPointInRect(x, y, r) {
+			continue
+		}
 
-			snapX := x % 32
-			snapY := y % 32
+		if shift {
+			g.Selected = toggleTileSelection(g.Selected, tile)
+			return
+		}
 
-			tile := Tile{
-				X:     x - snapX,
-				Y:     y - snapY,
-				Image: g.Library.Sets[g.Menu.SelectedSet].Values[g.Menu.SelectedTile][g.Menu.SelectedOrientation].Image,
+		if tileSelected(g.Selected, tile) && len(g.Selected) > 1 {
+			drag := &CursorDragGroup{Origin: image.Point{X: x, Y: y}}
+			for _, t := range g.Selected {
+				drag.Tiles = append(drag.Tiles, t)
+				drag.FromX = append(drag.FromX, t.X)
+				drag.FromY = append(drag.FromY, t.Y)
 			}
+			g.GroupDrag = drag
+			return
+		}
 
-			drag.Target = &tile
-			g.CursorDrag = drag
-			return nil
+		g.Selected = []*Tile{tile}
+		drag := &CursorDrag{
+			Origin:        image.Point{X: x, Y: y},
+			OriginTargetX: tile.GetX(),
+			OriginTargetY: tile.GetY(),
 		}
+		drag.Target = tile
+		g.CursorDrag = drag
+		return
+	}
 
-		// If the user is not dragging the menu, check if they are dragging an existing tile
-		for i := 0; i < len(g.Tiles); i++ {
-			tile := g.Tiles[i]
-			w, h = tile.Image.Size()
-			r = image.Rect(tile.X, tile.Y, tile.X+w, tile.Y+h)
+	// Nothing was hit: start a rubber-band selection.
+	g.SelectOrigin = &image.Point{X: x, Y: y}
+}
 
-			if IsPointInRect(x, y, r) {
-				drag := &CursorDrag{
-					Origin: image.Point{X: x, Y: y},
-				}
-				drag.Target = tile
-				g.CursorDrag = drag
-				return nil
-			}
-		}
-	} else if g.CursorDrag != nil && inpututil.IsMouseButtonJustReleased(ebiten.MouseButtonLeft) {
-		x, y := ebiten.CursorPosition()
+// handleMouseUp finishes the in-progress CursorDrag, CursorDragGroup, or
+// rubber-band selection (if any): it snaps the dragged target into place,
+// keeps it in frame, and records an undo command for the move or new
+// placement.
+func (g *Game) handleMouseUp(x, y int, shift bool) {
+	if g.GroupDrag != nil {
+		g.finishGroupDrag(x, y)
+		return
+	}
 
-		xDiff := x - g.CursorDrag.Origin.X
-		if xDiff < 0 {
-			xDiff += 32
+	if g.SelectOrigin != nil {
+		g.finishSelectBox(x, y, shift)
+		return
+	}
+
+	if g.CursorDrag == nil {
+		return
+	}
+
+	xDiff := x - g.CursorDrag.Origin.X
+	if xDiff < 0 {
+		xDiff += 32
+	}
+
+	yDiff := y - g.CursorDrag.Origin.Y
+	if yDiff < 0 {
+		yDiff += 32
+	}
+
+	snapX := xDiff % 32
+	snapY := yDiff % 32
+
+	g.CursorDrag.Target.SetX(g.CursorDrag.Target.GetX() + x - g.CursorDrag.Origin.X - snapX)
+	g.CursorDrag.Target.SetY(g.CursorDrag.Target.GetY() + y - g.CursorDrag.Origin.Y - snapY)
+
+	// Keep the target in frame so that it doesn't disappear
+	if g.CursorDrag.Target == &g.Menu {
+		g.CursorDrag.Target.SetY(Max(0, g.CursorDrag.Target.GetY()))
+		g.CursorDrag.Target.SetX(Max(0, g.CursorDrag.Target.GetX()))
+	} else {
+		bounds := g.CursorDrag.Target.GetImage().Bounds()
+		boundsX := bounds.Dx()
+		boundsY := bounds.Dy()
+		targetX := g.CursorDrag.Target.GetX()
+		targetY := g.CursorDrag.Target.GetY()
+		bufferX := (-targetX/32+1)*32 - boundsX
+		bufferY := (-targetY/32+1)*32 - boundsY
+
+		if targetX <= -boundsX {
+			g.CursorDrag.Target.SetX(targetX + bufferX)
 		}
+		if targetY <= -boundsY {
+			g.CursorDrag.Target.SetY(targetY + bufferY)
+		}
+	}
 
-		yDiff := y - g.CursorDrag.Origin.Y
-		if yDiff < 0 {
-			yDiff += 32
+	if g.CursorDrag.IsNewTile {
+		w, h := g.Menu.Image.Size()
+		r := image.Rect(g.Menu.X, g.Menu.Y, g.Menu.X+w, g.Menu.Y+h)
+		// If a new tile is in the menu area, discard it on click release
+		if !IsPointInRect(x, y, r) {
+			newTile := g.CursorDrag.Target.(*Tile)
+			g.History.Push(g, &PlaceCommand{
+				Tile: &Tile{
+					X:           newTile.GetX(),
+					Y:           newTile.GetY(),
+					Name:        newTile.Name,
+					Set:         newTile.Set,
+					Orientation: newTile.Orientation,
+					Image:       newTile.GetImage(),
+					Walkable:    newTile.Walkable,
+				},
+			})
+		}
+	} else {
+		switch target := g.CursorDrag.Target.(type) {
+		case *Tile:
+			g.History.Push(g, &MoveCommand{
+				Tile:  target,
+				FromX: g.CursorDrag.OriginTargetX,
+				FromY: g.CursorDrag.OriginTargetY,
+				ToX:   target.X,
+				ToY:   target.Y,
+				At:    time.Now(),
+			})
+		case *Menu:
+			g.History.Push(g, &MenuMoveCommand{
+				Menu:  target,
+				FromX: g.CursorDrag.OriginTargetX,
+				FromY: g.CursorDrag.OriginTargetY,
+				ToX:   target.X,
+				ToY:   target.Y,
+				At:    time.Now(),
+			})
 		}
+	}
+	g.CursorDrag = nil
+}
 
-		snapX := xDiff % 32
-		snapY := yDiff % 32
+// finishGroupDrag snaps every tile in g.GroupDrag to its new position,
+// preserving relative offsets, and records it as one undoable move.
+func (g *Game) finishGroupDrag(x, y int) {
+	xDiff := x - g.GroupDrag.Origin.X
+	if xDiff < 0 {
+		xDiff += 32
+	}
 
-		g.CursorDrag.Target.SetX(g.CursorDrag.Target.GetX() + x - g.CursorDrag.Origin.X - snapX)
-		g.CursorDrag.Target.SetY(g.CursorDrag.Target.GetY() + y - g.CursorDrag.Origin.Y - snapY)
+	yDiff := y - g.GroupDrag.Origin.Y
+	if yDiff < 0 {
+		yDiff += 32
+	}
 
-		// Keep the target in frame so that it doesn't disappear
-		if g.CursorDrag.Target == &g.Menu {
-			g.CursorDrag.Target.SetY(Max(0, g.CursorDrag.Target.GetY()))
-			g.CursorDrag.Target.SetX(Max(0, g.CursorDrag.Target.GetX()))
-		} else {
-			bounds := g.CursorDrag.Target.GetImage().Bounds()
-			boundsX := bounds.Dx()
-			boundsY := bounds.Dy()
-			targetX := g.CursorDrag.Target.GetX()
-			targetY := g.CursorDrag.Target.GetY()
-			bufferX := (-targetX/32+1)*32 - boundsX
-			bufferY := (-targetY/32+1)*32 - boundsY
+	dx := x - g.GroupDrag.Origin.X - xDiff%32
+	dy := y - g.GroupDrag.Origin.Y - yDiff%32
 
-			if targetX <= -boundsX {
-				g.CursorDrag.Target.SetX(targetX + bufferX)
-			}
-			if targetY <= -boundsY {
-				g.CursorDrag.Target.SetY(targetY + bufferY)
-			}
+	toX := make([]int, len(g.GroupDrag.Tiles))
+	toY := make([]int, len(g.GroupDrag.Tiles))
+	for i, t := range g.GroupDrag.Tiles {
+		t.X = g.GroupDrag.FromX[i] + dx
+		t.Y = g.GroupDrag.FromY[i] + dy
+		toX[i] = t.X
+		toY[i] = t.Y
+	}
+
+	g.History.Push(g, &GroupMoveCommand{
+		Tiles: g.GroupDrag.Tiles,
+		FromX: g.GroupDrag.FromX,
+		FromY: g.GroupDrag.FromY,
+		ToX:   toX,
+		ToY:   toY,
+		At:    time.Now(),
+	})
+	g.GroupDrag = nil
+}
+
+// finishSelectBox resolves an in-progress rubber-band selection: a plain
+// click clears the selection, while a drag selects every tile whose
+// bounds overlap the box. shift adds to the existing selection instead
+// of replacing it.
+func (g *Game) finishSelectBox(x, y int, shift bool) {
+	origin := *g.SelectOrigin
+	g.SelectOrigin = nil
+
+	minX, maxX := Min(origin.X, x), Max(origin.X, x)
+	minY, maxY := Min(origin.Y, y), Max(origin.Y, y)
+
+	if maxX-minX < 2 && maxY-minY < 2 {
+		if !shift {
+			g.Selected = nil
 		}
+		return
+	}
 
-		if g.CursorDrag.IsNewTile {
-			w, h := g.Menu.Image.Size()
-			r := image.Rect(g.Menu.X, g.Menu.Y, g.Menu.X+w, g.Menu.Y+h)
-			// If a new tile is in the menu area, discard it on click release
-			if !IsPointInRect(x, y, r) {
-				g.Tiles = append(g.Tiles, &Tile{
-					X:     g.CursorDrag.Target.GetX(),
-					Y:     g.CursorDrag.Target.GetY(),
-					Image: g.CursorDrag.Target.GetImage(),
-				})
-			}
+	box := image.Rect(minX, minY, maxX, maxY)
+	var hits []*Tile
+	for _, t := range g.Tiles {
+		w, h := t.Image.Size()
+		if box.Overlaps(image.Rect(t.X, t.Y, t.X+w, t.Y+h)) {
+			hits = append(hits, t)
 		}
-		g.CursorDrag = nil
 	}
 
-	return nil
+	if !shift {
+		g.Selected = hits
+		return
+	}
+
+	for _, t := range hits {
+		if !tileSelected(g.Selected, t) {
+			g.Selected = append(g.Selected, t)
+		}
+	}
 }
 
 func (g *Game) Draw(screen *ebiten.Image) {
@@ -328,27 +723,98 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	}
 
 	for _, tile := range g.Tiles {
-		if g.CursorDrag == nil || tile != g.CursorDrag.Target {
+		if g.CursorDrag != nil && tile == g.CursorDrag.Target {
+			continue
+		}
+		if g.GroupDrag != nil && tileSelected(g.GroupDrag.Tiles, tile) {
+			continue
+		}
+
+		op.GeoM.Reset()
+		op.GeoM.Translate(float64(tile.X), float64(tile.Y))
+		screen.DrawImage(tile.Image, op)
+
+		if tileSelected(g.Selected, tile) {
+			w, h := tile.Image.Size()
+			drawSelectionOutline(screen, tile.X, tile.Y, w, h)
+		}
+	}
+
+	if g.GroupDrag != nil {
+		x, y := ebiten.CursorPosition()
+		xDiff := x - g.GroupDrag.Origin.X
+		if xDiff < 0 {
+			xDiff += 32
+		}
+		yDiff := y - g.GroupDrag.Origin.Y
+		if yDiff < 0 {
+			yDiff += 32
+		}
+		dx := x - g.GroupDrag.Origin.X - xDiff%32
+		dy := y - g.GroupDrag.Origin.Y - yDiff%32
+
+		for i, t := range g.GroupDrag.Tiles {
 			op.GeoM.Reset()
-			op.GeoM.Translate(float64(tile.X), float64(tile.Y))
-			screen.DrawImage(tile.Image, op)
+			op.GeoM.Translate(float64(g.GroupDrag.FromX[i]+dx), float64(g.GroupDrag.FromY[i]+dy))
+			op.ColorM.Scale(1, 1, 1, 0.5)
+			screen.DrawImage(t.Image, op)
 		}
 	}
 
+	for _, t := range g.Paste {
+		op.GeoM.Reset()
+		op.GeoM.Translate(float64(t.X), float64(t.Y))
+		op.ColorM.Scale(1, 1, 1, 0.5)
+		screen.DrawImage(t.Image, op)
+	}
+
+	if g.SelectOrigin != nil {
+		x, y := ebiten.CursorPosition()
+		minX, maxX := Min(g.SelectOrigin.X, x), Max(g.SelectOrigin.X, x)
+		minY, maxY := Min(g.SelectOrigin.Y, y), Max(g.SelectOrigin.Y, y)
+		ebitenutil.DrawRect(screen, float64(minX), float64(minY), float64(maxX-minX), float64(maxY-minY), color.RGBA{B: 255, A: 96})
+	}
+
+	if g.ShowWalkable {
+		for cy := 0; cy < g.Grid.Y; cy++ {
+			for cx := 0; cx < g.Grid.X; cx++ {
+				overlay := color.RGBA{R: 255, A: 64}
+				if g.isWalkable(cx, cy) {
+					overlay = color.RGBA{G: 255, A: 64}
+				}
+				ebitenutil.DrawRect(screen, float64(cx*32), float64(cy*32), 32, 32, overlay)
+			}
+		}
+	}
+
+	for i := 1; i < len(g.PathPreview); i++ {
+		a, b := g.PathPreview[i-1], g.PathPreview[i]
+		x1, y1 := float64(a.X*32+16), float64(a.Y*32+16)
+		x2, y2 := float64(b.X*32+16), float64(b.Y*32+16)
+		ebitenutil.DrawLine(screen, x1, y1, x2, y2, color.RGBA{B: 255, A: 192})
+	}
+
 	if g.Save {
 		g.Save = false
-		buf := new(bytes.Buffer)
-		png.Encode(buf, screen)
+		if err := ExportPNG(screen, "./screen.png"); err != nil {
+			log.Println("export png:", err)
+		}
+		if err := SaveProject(g, projectPath); err != nil {
+			log.Println("save project:", err)
+		}
+	}
 
-		f, err := os.Create("./screen.png")
-		if err != nil {
-			log.Fatal(err)
+	if g.Load {
+		g.Load = false
+		if err := LoadProject(g, projectPath); err != nil {
+			log.Println("load project:", err)
 		}
-		defer f.Close()
+	}
 
-		_, err = io.Copy(f, buf)
-		if err != nil {
-			log.Fatal(err)
+	if g.ExportPDF {
+		g.ExportPDF = false
+		if err := ExportPDF(screen, "./screen.pdf"); err != nil {
+			log.Println("export pdf:", err)
 		}
 	}
 
@@ -380,6 +846,26 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	op.GeoM.Translate(float64(g.Menu.X)+saveOffsetX, float64(g.Menu.Y+saveOffsetY))
 	screen.DrawImage(g.Menu.SaveButton, op)
 
+	op.GeoM.Reset()
+	op.GeoM.Translate(float64(g.Menu.X)+loadOffsetX, float64(g.Menu.Y+loadOffsetY))
+	screen.DrawImage(g.Menu.LoadButton, op)
+
+	op.GeoM.Reset()
+	op.GeoM.Translate(float64(g.Menu.X)+undoOffsetX, float64(g.Menu.Y+undoOffsetY))
+	screen.DrawImage(g.Menu.UndoButton, op)
+
+	op.GeoM.Reset()
+	op.GeoM.Translate(float64(g.Menu.X)+redoOffsetX, float64(g.Menu.Y+redoOffsetY))
+	screen.DrawImage(g.Menu.RedoButton, op)
+
+	op.GeoM.Reset()
+	op.GeoM.Translate(float64(g.Menu.X)+pathOffsetX, float64(g.Menu.Y+pathOffsetY))
+	screen.DrawImage(g.Menu.PathButton, op)
+
+	op.GeoM.Reset()
+	op.GeoM.Translate(float64(g.Menu.X)+walkableOffsetX, float64(g.Menu.Y+walkableOffsetY))
+	screen.DrawImage(g.Menu.WalkableButton, op)
+
 	op.GeoM.Reset()
 	op.GeoM.Translate(float64(g.Menu.X)+dropdownBarOffsetX, float64(g.Menu.Y+dropdownOffsetY))
 	screen.DrawImage(g.Menu.DropdownBar, op)
@@ -476,9 +962,10 @@ func main() {
 		}
 
 		values[set][n][o] = Tile{
-			Size:  size,
-			Name:  n,
-			Image: image,
+			Size:     size,
+			Name:     n,
+			Image:    image,
+			Walkable: loadWalkMask("./tiles/" + name + ".walk"),
 		}
 	}
 
@@ -548,6 +1035,31 @@ func main() {
 		log.Fatal(err)
 	}
 
+	load, _, err := ebitenutil.NewImageFromFile("./load_icon.png")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	undo, _, err := ebitenutil.NewImageFromFile("./undo_icon.png")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	redo, _, err := ebitenutil.NewImageFromFile("./redo_icon.png")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	path, _, err := ebitenutil.NewImageFromFile("./path_icon.png")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	walkable, _, err := ebitenutil.NewImageFromFile("./walkable_icon.png")
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	f, err := opentype.Parse(goregular.TTF)
 	if err != nil {
 		log.Fatal(err)
@@ -562,9 +1074,16 @@ func main() {
 		log.Fatal(err)
 	}
 
+	keymap, err := LoadKeymap("./keymap.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	g := &Game{
 		Library: library,
 		Tiles:   []*Tile{},
+		History: NewUndoStack(),
+		Keymap:  keymap,
 		Grid: Grid{
 			X:     39,
 			Y:     22,
@@ -580,6 +1099,11 @@ func main() {
 			RotateLeft:          rotateLeft,
 			RotateRight:         rotateRight,
 			SaveButton:          save,
+			LoadButton:          load,
+			UndoButton:          undo,
+			RedoButton:          redo,
+			PathButton:          path,
+			WalkableButton:      walkable,
 			SelectedSet:         "Dungeon Tiles",
 			SelectedTile:        library.Sets["Dungeon Tiles"].Keys[0],
 			SelectedOrientation: 0,
@@ -587,11 +1111,35 @@ func main() {
 		Font: face,
 	}
 
+	if err := loadLatestProject(g); err != nil {
+		log.Println("load project:", err)
+	}
+
 	if err := ebiten.RunGame(g); err != nil {
 		log.Fatal(err)
 	}
 }
 
+// loadLatestProject restores g from whichever of projectPath and
+// autosavePath was written most recently, so a crash or an unsaved close
+// loses at most autosaveEveryTicks worth of work. Neither file existing is
+// not an error: it just means this is a fresh project.
+func loadLatestProject(g *Game) error {
+	path := projectPath
+
+	if autosaveInfo, err := os.Stat(autosavePath); err == nil {
+		if projectInfo, err := os.Stat(projectPath); err != nil || autosaveInfo.ModTime().After(projectInfo.ModTime()) {
+			path = autosavePath
+		}
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	return LoadProject(g, path)
+}
+
 func Max(x, y int) int {
 	if x > y {
 		return x
@@ -599,6 +1147,25 @@ func Max(x, y int) int {
 	return y
 }
 
+func Min(x, y int) int {
+	if x < y {
+		return x
+	}
+	return y
+}
+
+// drawSelectionOutline draws a one-pixel rectangle around a selected
+// tile's bounds.
+func drawSelectionOutline(screen *ebiten.Image, x, y, w, h int) {
+	c := color.RGBA{R: 255, G: 255, A: 255}
+	x1, y1 := float64(x), float64(y)
+	x2, y2 := float64(x+w), float64(y+h)
+	ebitenutil.DrawLine(screen, x1, y1, x2, y1, c)
+	ebitenutil.DrawLine(screen, x2, y1, x2, y2, c)
+	ebitenutil.DrawLine(screen, x2, y2, x1, y2, c)
+	ebitenutil.DrawLine(screen, x1, y2, x1, y1, c)
+}
+
 type Library struct {
 	Sets map[string]Set
 }
@@ -0,0 +1,95 @@
+package main
+
+import "testing"
+
+// newTestGame returns a Game with just enough state wired up to drive
+// Advance with synthetic KeyPress events, without needing real tile or
+// menu button images.
+func newTestGame() *Game {
+	return &Game{
+		History: NewUndoStack(),
+		Keymap:  DefaultKeymap(),
+	}
+}
+
+func TestAdvanceRotate(t *testing.T) {
+	g := newTestGame()
+
+	if err := g.Advance([]InputEvent{{Kind: KeyPress, Action: ActionRotateRight}}); err != nil {
+		t.Fatalf("Advance: %v", err)
+	}
+	if g.Menu.SelectedOrientation != 90 {
+		t.Fatalf("SelectedOrientation = %d, want 90", g.Menu.SelectedOrientation)
+	}
+
+	events := []InputEvent{
+		{Kind: KeyPress, Action: ActionRotateLeft},
+		{Kind: KeyPress, Action: ActionRotateLeft},
+	}
+	if err := g.Advance(events); err != nil {
+		t.Fatalf("Advance: %v", err)
+	}
+	if g.Menu.SelectedOrientation != 270 {
+		t.Fatalf("SelectedOrientation = %d, want 270 (wrapped below zero)", g.Menu.SelectedOrientation)
+	}
+}
+
+func TestAdvancePaging(t *testing.T) {
+	g := newTestGame()
+
+	g.Advance([]InputEvent{{Kind: KeyPress, Action: ActionPrevPage}})
+	if g.Menu.Page != 0 {
+		t.Fatalf("Page = %d, want 0 (can't page below zero)", g.Menu.Page)
+	}
+
+	events := []InputEvent{
+		{Kind: KeyPress, Action: ActionNextPage},
+		{Kind: KeyPress, Action: ActionNextPage},
+	}
+	g.Advance(events)
+	if g.Menu.Page != 2 {
+		t.Fatalf("Page = %d, want 2", g.Menu.Page)
+	}
+
+	g.Advance([]InputEvent{{Kind: KeyPress, Action: ActionPrevPage}})
+	if g.Menu.Page != 1 {
+		t.Fatalf("Page = %d, want 1", g.Menu.Page)
+	}
+}
+
+func TestAdvanceUndoRedo(t *testing.T) {
+	g := newTestGame()
+	tile := &Tile{X: 0, Y: 0}
+	g.History.Push(g, &PlaceCommand{Tile: tile})
+
+	if len(g.Tiles) != 1 {
+		t.Fatalf("len(Tiles) = %d, want 1", len(g.Tiles))
+	}
+
+	g.Advance([]InputEvent{{Kind: KeyPress, Action: ActionUndo}})
+	if len(g.Tiles) != 0 {
+		t.Fatalf("len(Tiles) = %d after undo, want 0", len(g.Tiles))
+	}
+
+	g.Advance([]InputEvent{{Kind: KeyPress, Action: ActionRedo}})
+	if len(g.Tiles) != 1 {
+		t.Fatalf("len(Tiles) = %d after redo, want 1", len(g.Tiles))
+	}
+}
+
+func TestAdvanceDeleteSelection(t *testing.T) {
+	g := newTestGame()
+	tile := &Tile{X: 0, Y: 0}
+	g.Tiles = []*Tile{tile}
+	g.Selected = []*Tile{tile}
+
+	g.Advance([]InputEvent{{Kind: KeyPress, Action: ActionDelete}})
+	if len(g.Tiles) != 0 {
+		t.Fatalf("len(Tiles) = %d after delete, want 0", len(g.Tiles))
+	}
+
+	g.Advance([]InputEvent{{Kind: KeyPress, Action: ActionUndo}})
+	if len(g.Tiles) != 1 {
+		t.Fatalf("len(Tiles) = %d after undoing delete, want 1", len(g.Tiles))
+	}
+}
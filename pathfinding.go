@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"image"
+	"os"
+)
+
+// Movement costs follow the usual A* grid convention: 10 for an
+// orthogonal step, 14 (~10*sqrt(2)) for a diagonal one.
+const (
+	orthogonalCost = 10
+	diagonalCost   = 14
+)
+
+// octile is the admissible heuristic for 8-directional grid movement.
+func octile(dx, dy int) int {
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	if dx < dy {
+		dx, dy = dy, dx
+	}
+	return dx*orthogonalCost + dy*(diagonalCost-orthogonalCost)
+}
+
+var neighborOffsets = [8][2]int{
+	{1, 0}, {-1, 0}, {0, 1}, {0, -1},
+	{1, 1}, {1, -1}, {-1, 1}, {-1, -1},
+}
+
+type pathOpenEntry struct {
+	cell image.Point
+	f    int
+}
+
+type pathOpenHeap []pathOpenEntry
+
+func (h pathOpenHeap) Len() int            { return len(h) }
+func (h pathOpenHeap) Less(i, j int) bool  { return h[i].f < h[j].f }
+func (h pathOpenHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *pathOpenHeap) Push(x interface{}) { *h = append(*h, x.(pathOpenEntry)) }
+func (h *pathOpenHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// AStar finds the shortest walkable path from start to goal on a
+// width x height grid of cells, calling walkable to test whether a cell
+// can be entered. Diagonal moves that would cut a corner through two
+// unwalkable cells are disallowed. It returns the path, start and goal
+// inclusive, and whether one was found.
+func AStar(start, goal image.Point, width, height int, walkable func(x, y int) bool) ([]image.Point, bool) {
+	inBounds := func(x, y int) bool {
+		return x >= 0 && x < width && y >= 0 && y < height
+	}
+
+	if !inBounds(start.X, start.Y) || !inBounds(goal.X, goal.Y) {
+		return nil, false
+	}
+	if !walkable(start.X, start.Y) || !walkable(goal.X, goal.Y) {
+		return nil, false
+	}
+
+	gScore := map[image.Point]int{start: 0}
+	cameFrom := map[image.Point]image.Point{}
+	visited := map[image.Point]bool{}
+
+	open := &pathOpenHeap{{cell: start, f: octile(goal.X-start.X, goal.Y-start.Y)}}
+	heap.Init(open)
+
+	for open.Len() > 0 {
+		cur := heap.Pop(open).(pathOpenEntry).cell
+		if visited[cur] {
+			continue
+		}
+		visited[cur] = true
+
+		if cur == goal {
+			return reconstructPath(cameFrom, cur), true
+		}
+
+		for _, off := range neighborOffsets {
+			next := image.Point{X: cur.X + off[0], Y: cur.Y + off[1]}
+			if !inBounds(next.X, next.Y) || !walkable(next.X, next.Y) {
+				continue
+			}
+
+			cost := orthogonalCost
+			if off[0] != 0 && off[1] != 0 {
+				// Disallow cutting a corner through two unwalkable cells;
+				// sliding past a single blocked corner is still fine.
+				if !walkable(cur.X+off[0], cur.Y) && !walkable(cur.X, cur.Y+off[1]) {
+					continue
+				}
+				cost = diagonalCost
+			}
+
+			tentative := gScore[cur] + cost
+			if g, ok := gScore[next]; !ok || tentative < g {
+				gScore[next] = tentative
+				cameFrom[next] = cur
+				heap.Push(open, pathOpenEntry{cell: next, f: tentative + octile(goal.X-next.X, goal.Y-next.Y)})
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func reconstructPath(cameFrom map[image.Point]image.Point, cur image.Point) []image.Point {
+	path := []image.Point{cur}
+	for {
+		prev, ok := cameFrom[cur]
+		if !ok {
+			return path
+		}
+		path = append([]image.Point{prev}, path...)
+		cur = prev
+	}
+}
+
+// loadWalkMask reads a sidecar .walk file next to a tile image: one line
+// per row of 32x32 cells, '#' for unwalkable and anything else for
+// walkable. A missing sidecar is not an error; it just means the tile is
+// fully walkable.
+func loadWalkMask(path string) [][]bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var mask [][]bool
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		row := make([]bool, len(line))
+		for i, r := range line {
+			row[i] = r != '#'
+		}
+		mask = append(mask, row)
+	}
+
+	return mask
+}
+
+// cellAt converts a screen position to a grid cell, if it falls within
+// the grid's bounds.
+func (g *Game) cellAt(x, y int) (image.Point, bool) {
+	if x < 0 || y < 0 || x >= g.Grid.X*32 || y >= g.Grid.Y*32 {
+		return image.Point{}, false
+	}
+	return image.Point{X: x / 32, Y: y / 32}, true
+}
+
+// isWalkable reports whether grid cell (cx, cy) can be entered: open
+// floor is always walkable, and a cell covered by a tile defers to that
+// tile's Walkable mask (or is walkable if the tile has no mask).
+func (g *Game) isWalkable(cx, cy int) bool {
+	px, py := cx*32, cy*32
+
+	for _, t := range g.Tiles {
+		w, h := t.Image.Size()
+		if px < t.X || px >= t.X+w || py < t.Y || py >= t.Y+h {
+			continue
+		}
+
+		if t.Walkable == nil {
+			return true
+		}
+
+		localX, localY := (px-t.X)/32, (py-t.Y)/32
+		if localY >= len(t.Walkable) || localX >= len(t.Walkable[localY]) {
+			return true
+		}
+		return t.Walkable[localY][localX]
+	}
+
+	return true
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+func TestAStarFindsPath(t *testing.T) {
+	walkable := func(x, y int) bool { return true }
+
+	path, ok := AStar(image.Pt(0, 0), image.Pt(3, 0), 5, 5, walkable)
+	if !ok {
+		t.Fatalf("expected a path on an open grid")
+	}
+	if len(path) == 0 || path[0] != image.Pt(0, 0) || path[len(path)-1] != image.Pt(3, 0) {
+		t.Fatalf("path = %v, want it to start at (0,0) and end at (3,0)", path)
+	}
+}
+
+func TestAStarNoPath(t *testing.T) {
+	blocked := map[image.Point]bool{
+		image.Pt(1, 0): true,
+		image.Pt(1, 1): true,
+		image.Pt(1, 2): true,
+		image.Pt(1, 3): true,
+		image.Pt(1, 4): true,
+	}
+	walkable := func(x, y int) bool { return !blocked[image.Pt(x, y)] }
+
+	if _, ok := AStar(image.Pt(0, 0), image.Pt(4, 0), 5, 5, walkable); ok {
+		t.Fatalf("expected no path through a solid wall spanning the grid")
+	}
+}
+
+func TestAStarDisallowsCornerCutting(t *testing.T) {
+	// (2,1) and (1,2) are both unwalkable, so the diagonal step from
+	// (1,1) to (2,2) would cut through a blocked corner and must be
+	// rejected in favor of a longer detour around the blocked cells.
+	blocked := map[image.Point]bool{image.Pt(2, 1): true, image.Pt(1, 2): true}
+	walkable := func(x, y int) bool { return !blocked[image.Pt(x, y)] }
+
+	path, ok := AStar(image.Pt(0, 0), image.Pt(2, 2), 5, 5, walkable)
+	if !ok {
+		t.Fatalf("expected a detour to still find a path")
+	}
+	for _, p := range path {
+		if blocked[p] {
+			t.Fatalf("path %v should not pass through a blocked corner cell", path)
+		}
+	}
+	if len(path) <= 3 {
+		t.Fatalf("path = %v, want a detour longer than the disallowed direct diagonal", path)
+	}
+}
+
+func TestAStarAllowsSlidingPastOneBlockedCorner(t *testing.T) {
+	// Only (2,1) is unwalkable; (1,2) is open, so the diagonal from
+	// (1,1) to (2,2) slides past a single blocked corner rather than
+	// cutting through two, and should be allowed.
+	blocked := map[image.Point]bool{image.Pt(2, 1): true}
+	walkable := func(x, y int) bool { return !blocked[image.Pt(x, y)] }
+
+	path, ok := AStar(image.Pt(1, 1), image.Pt(2, 2), 5, 5, walkable)
+	if !ok {
+		t.Fatalf("expected a path from (1,1) to (2,2)")
+	}
+	if len(path) != 2 {
+		t.Fatalf("path = %v, want the direct diagonal step (1,1) -> (2,2)", path)
+	}
+}